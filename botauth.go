@@ -0,0 +1,477 @@
+package telegramauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBotAuthTokenTTL is how long a requested login token stays valid.
+	DefaultBotAuthTokenTTL = 5 * time.Minute
+	// DefaultBotAuthPollInterval is the default getUpdates long-poll cadence.
+	DefaultBotAuthPollInterval = 5 * time.Second
+	// DefaultBotAuthSweepInterval is the default cadence for evicting expired tokens.
+	DefaultBotAuthSweepInterval = 5 * time.Minute
+	// defaultGetUpdatesTimeout is the long-poll timeout sent to Telegram, in seconds.
+	defaultGetUpdatesTimeout = 4
+)
+
+var (
+	// ErrBotTokenEmpty indicates that NewBotAuth was called without a bot token.
+	ErrBotTokenEmpty = errors.New("bot token is required")
+	// ErrBotUsernameEmpty indicates that BotAuthOptions.BotUsername is missing.
+	ErrBotUsernameEmpty = errors.New("bot username is required")
+	// ErrBotAuthTokenNotFound indicates that Poll was called with an unknown or expired token.
+	ErrBotAuthTokenNotFound = errors.New("bot auth token not found")
+	// ErrBotAuthPending indicates that the token is still waiting for the user to message the bot.
+	ErrBotAuthPending = errors.New("bot auth token not yet confirmed")
+)
+
+// BotAuthEntry is the state a BotAuthStore holds for a single login token.
+type BotAuthEntry struct {
+	// ExpiresAt is when the token should stop being accepted.
+	ExpiresAt time.Time
+	// Data is nil until the user has confirmed the token via the bot.
+	Data *AuthData
+}
+
+// BotAuthStore persists pending and confirmed login tokens for TelegramBotAuth.
+// The default implementation is an in-memory map; callers that need durability
+// or multi-instance sharing can back it with Redis or SQL instead.
+type BotAuthStore interface {
+	// Create registers a new pending token that expires at expiresAt.
+	Create(ctx context.Context, token string, expiresAt time.Time) error
+	// Resolve marks token as confirmed with the given AuthData. It reports
+	// whether a matching pending token was found.
+	Resolve(ctx context.Context, token string, data AuthData) (bool, error)
+	// Get returns the current entry for token.
+	Get(ctx context.Context, token string) (BotAuthEntry, bool, error)
+	// Delete removes token, regardless of whether it was ever confirmed.
+	Delete(ctx context.Context, token string) error
+	// Sweep removes entries that expired before now.
+	Sweep(ctx context.Context, now time.Time) error
+}
+
+// BotAuthOptions configures NewBotAuth.
+type BotAuthOptions struct {
+	// BotUsername is the bot's @username, used to build deep links.
+	BotUsername string
+	// TokenTTL is how long a requested token remains valid.
+	// Zero value uses DefaultBotAuthTokenTTL.
+	TokenTTL time.Duration
+	// PollInterval is the cadence at which Run calls getUpdates.
+	// Zero value uses DefaultBotAuthPollInterval.
+	PollInterval time.Duration
+	// SweepInterval is the cadence at which Run evicts expired tokens.
+	// Zero value uses DefaultBotAuthSweepInterval.
+	SweepInterval time.Duration
+	// Store persists pending and confirmed tokens.
+	// Nil uses NewMemoryBotAuthStore().
+	Store BotAuthStore
+	// HTTPClient is used for Telegram Bot API requests.
+	// Nil uses http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// BotAuth issues short-lived login tokens that a user confirms by sending
+// "/start <token>" to a Telegram bot, as an alternative to the Login Widget
+// that doesn't depend on embedding Telegram's JS widget in a page.
+type BotAuth struct {
+	botToken string
+	opts     BotAuthOptions
+	store    BotAuthStore
+	client   *http.Client
+
+	offsetMu sync.Mutex
+	offset   int64
+}
+
+// NewBotAuth constructs a BotAuth for the given bot token.
+func NewBotAuth(botToken string, opts BotAuthOptions) (*BotAuth, error) {
+	botToken = strings.TrimSpace(botToken)
+	if botToken == "" {
+		return nil, ErrBotTokenEmpty
+	}
+
+	if strings.TrimSpace(opts.BotUsername) == "" {
+		return nil, ErrBotUsernameEmpty
+	}
+
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = DefaultBotAuthTokenTTL
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultBotAuthPollInterval
+	}
+
+	if opts.SweepInterval <= 0 {
+		opts.SweepInterval = DefaultBotAuthSweepInterval
+	}
+
+	if opts.Store == nil {
+		opts.Store = NewMemoryBotAuthStore()
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return &BotAuth{
+		botToken: botToken,
+		opts:     opts,
+		store:    opts.Store,
+		client:   opts.HTTPClient,
+	}, nil
+}
+
+// Request issues a new login token and the deep link the user should open
+// to confirm it via the bot.
+func (a *BotAuth) Request(ctx context.Context) (token string, deepLink string, err error) {
+	token, err = randomToken()
+	if err != nil {
+		return "", "", fmt.Errorf("generate token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(a.opts.TokenTTL)
+	if err := a.store.Create(ctx, token, expiresAt); err != nil {
+		return "", "", fmt.Errorf("create token: %w", err)
+	}
+
+	deepLink = fmt.Sprintf("https://t.me/%s?start=%s", a.opts.BotUsername, token)
+
+	return token, deepLink, nil
+}
+
+// Poll reports whether token has been confirmed yet. It returns
+// ErrBotAuthPending while waiting and ErrBotAuthTokenNotFound once the token
+// is unknown or has expired. A confirmed token is deleted from the store as
+// soon as it is returned, so it is a single-use bearer credential rather
+// than one valid for the rest of its TokenTTL.
+func (a *BotAuth) Poll(ctx context.Context, token string) (AuthData, error) {
+	entry, ok, err := a.store.Get(ctx, token)
+	if err != nil {
+		return AuthData{}, fmt.Errorf("get token: %w", err)
+	}
+
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return AuthData{}, ErrBotAuthTokenNotFound
+	}
+
+	if entry.Data == nil {
+		return AuthData{}, ErrBotAuthPending
+	}
+
+	if err := a.store.Delete(ctx, token); err != nil {
+		return AuthData{}, fmt.Errorf("delete token: %w", err)
+	}
+
+	return *entry.Data, nil
+}
+
+// Run drives the getUpdates long-poll loop and the expired-token sweep until
+// ctx is cancelled, returning nil on clean shutdown. If either loop returns
+// a non-context error (e.g. a persistent getUpdates failure), Run cancels
+// the other loop and surfaces that error instead of hanging indefinitely.
+func (a *BotAuth) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 2)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		err := a.runPollLoop(runCtx)
+		if err != nil && !isContextDone(err) {
+			cancel()
+		}
+		errCh <- err
+	}()
+
+	go func() {
+		defer wg.Done()
+		err := a.runSweepLoop(runCtx)
+		if err != nil && !isContextDone(err) {
+			cancel()
+		}
+		errCh <- err
+	}()
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil && !isContextDone(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isContextDone reports whether err is one of the errors a context returns
+// for its own expected lifecycle end (explicit cancellation or deadline),
+// as opposed to a real failure that should be surfaced from Run.
+func isContextDone(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (a *BotAuth) runPollLoop(ctx context.Context) error {
+	ticker := time.NewTicker(a.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if retryAfter, err := a.fetchAndMatchUpdates(ctx); err != nil {
+				return err
+			} else if retryAfter > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(retryAfter):
+				}
+			}
+		}
+	}
+}
+
+func (a *BotAuth) runSweepLoop(ctx context.Context) error {
+	ticker := time.NewTicker(a.opts.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := a.store.Sweep(ctx, time.Now()); err != nil {
+				return fmt.Errorf("sweep expired tokens: %w", err)
+			}
+		}
+	}
+}
+
+// fetchAndMatchUpdates calls getUpdates once, matches any "/start <token>"
+// messages against pending tokens, and advances the update offset. It
+// returns a non-zero retryAfter when Telegram asked the caller to back off.
+func (a *BotAuth) fetchAndMatchUpdates(ctx context.Context) (retryAfter time.Duration, err error) {
+	a.offsetMu.Lock()
+	offset := a.offset
+	a.offsetMu.Unlock()
+
+	updates, retryAfter, err := a.getUpdates(ctx, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	if retryAfter > 0 {
+		return retryAfter, nil
+	}
+
+	for _, update := range updates {
+		if update.UpdateID >= offset {
+			offset = update.UpdateID + 1
+		}
+
+		token, ok := startCommandToken(update.Message.Text)
+		if !ok {
+			continue
+		}
+
+		data := AuthData{
+			UserID:       update.Message.From.ID,
+			Username:     update.Message.From.Username,
+			FirstName:    update.Message.From.FirstName,
+			LastName:     update.Message.From.LastName,
+			AuthDateUnix: time.Now().Unix(),
+		}
+
+		if _, err := a.store.Resolve(ctx, token, data); err != nil {
+			return 0, fmt.Errorf("resolve token: %w", err)
+		}
+	}
+
+	a.offsetMu.Lock()
+	a.offset = offset
+	a.offsetMu.Unlock()
+
+	return 0, nil
+}
+
+func startCommandToken(text string) (string, bool) {
+	const prefix = "/start "
+	if strings.HasPrefix(text, prefix) {
+		return strings.TrimSpace(strings.TrimPrefix(text, prefix)), true
+	}
+
+	return "", false
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  struct {
+		Text string `json:"text"`
+		From struct {
+			ID        int64  `json:"id"`
+			Username  string `json:"username"`
+			FirstName string `json:"first_name"`
+			LastName  string `json:"last_name"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+type getUpdatesResponse struct {
+	OK          bool             `json:"ok"`
+	Result      []telegramUpdate `json:"result"`
+	ErrorCode   int              `json:"error_code"`
+	Description string           `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (a *BotAuth) getUpdates(ctx context.Context, offset int64) ([]telegramUpdate, time.Duration, error) {
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates", a.botToken)
+
+	query := url.Values{}
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("timeout", strconv.Itoa(defaultGetUpdatesTimeout))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("build getUpdates request: %w", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("call getUpdates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, retryAfterFromResponse(resp), nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read getUpdates response: %w", err)
+	}
+
+	var decoded getUpdatesResponse
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, 0, fmt.Errorf("decode getUpdates response: %w", err)
+	}
+
+	if !decoded.OK {
+		if decoded.ErrorCode == http.StatusTooManyRequests {
+			return nil, time.Duration(decoded.Parameters.RetryAfter) * time.Second, nil
+		}
+
+		return nil, 0, fmt.Errorf("getUpdates failed: %s", decoded.Description)
+	}
+
+	return decoded.Result, 0, nil
+}
+
+func retryAfterFromResponse(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return time.Second
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// memoryBotAuthStore is the default in-memory, map+mutex BotAuthStore.
+type memoryBotAuthStore struct {
+	mu      sync.Mutex
+	entries map[string]BotAuthEntry
+}
+
+// NewMemoryBotAuthStore returns a BotAuthStore backed by an in-process map.
+// It is suitable for single-instance deployments; multi-instance deployments
+// should implement BotAuthStore against a shared store such as Redis.
+func NewMemoryBotAuthStore() BotAuthStore {
+	return &memoryBotAuthStore{entries: make(map[string]BotAuthEntry)}
+}
+
+func (s *memoryBotAuthStore) Create(_ context.Context, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[token] = BotAuthEntry{ExpiresAt: expiresAt}
+
+	return nil
+}
+
+func (s *memoryBotAuthStore) Resolve(_ context.Context, token string, data AuthData) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[token]
+	if !ok || entry.Data != nil {
+		return false, nil
+	}
+
+	entry.Data = &data
+	s.entries[token] = entry
+
+	return true, nil
+}
+
+func (s *memoryBotAuthStore) Get(_ context.Context, token string) (BotAuthEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[token]
+
+	return entry, ok, nil
+}
+
+func (s *memoryBotAuthStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, token)
+
+	return nil
+}
+
+func (s *memoryBotAuthStore) Sweep(_ context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, entry := range s.entries {
+		if now.After(entry.ExpiresAt) {
+			delete(s.entries, token)
+		}
+	}
+
+	return nil
+}
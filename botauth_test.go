@@ -0,0 +1,319 @@
+package telegramauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rewriteTransport redirects every request to base, regardless of the
+// request's original host, so tests can point BotAuth at an httptest.Server
+// standing in for api.telegram.org.
+type rewriteTransport struct {
+	base *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.base.Scheme
+	req.URL.Host = t.base.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestServerClient(t *testing.T, server *httptest.Server) *http.Client {
+	t.Helper()
+
+	base, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+
+	return &http.Client{Transport: &rewriteTransport{base: base}}
+}
+
+func TestNewBotAuthRequiresBotToken(t *testing.T) {
+	_, err := NewBotAuth("", BotAuthOptions{BotUsername: "mybot"})
+	if !errors.Is(err, ErrBotTokenEmpty) {
+		t.Fatalf("NewBotAuth() error = %v, want %v", err, ErrBotTokenEmpty)
+	}
+}
+
+func TestNewBotAuthRequiresBotUsername(t *testing.T) {
+	_, err := NewBotAuth(testBotToken, BotAuthOptions{})
+	if !errors.Is(err, ErrBotUsernameEmpty) {
+		t.Fatalf("NewBotAuth() error = %v, want %v", err, ErrBotUsernameEmpty)
+	}
+}
+
+func TestBotAuthRequestAndPoll(t *testing.T) {
+	ctx := context.Background()
+
+	auth, err := NewBotAuth(testBotToken, BotAuthOptions{BotUsername: "mybot"})
+	if err != nil {
+		t.Fatalf("NewBotAuth() error = %v", err)
+	}
+
+	token, deepLink, err := auth.Request(ctx)
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+
+	wantLink := "https://t.me/mybot?start=" + token
+	if deepLink != wantLink {
+		t.Fatalf("deepLink = %q, want %q", deepLink, wantLink)
+	}
+
+	if _, err := auth.Poll(ctx, token); !errors.Is(err, ErrBotAuthPending) {
+		t.Fatalf("Poll() error = %v, want %v", err, ErrBotAuthPending)
+	}
+
+	want := AuthData{UserID: 42, Username: "john_doe"}
+	if _, err := auth.store.Resolve(ctx, token, want); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	got, err := auth.Poll(ctx, token)
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	if got != want {
+		t.Fatalf("Poll() = %+v, want %+v", got, want)
+	}
+
+	if _, err := auth.Poll(ctx, token); !errors.Is(err, ErrBotAuthTokenNotFound) {
+		t.Fatalf("Poll() after consumption error = %v, want %v", err, ErrBotAuthTokenNotFound)
+	}
+}
+
+func TestBotAuthPollUnknownToken(t *testing.T) {
+	ctx := context.Background()
+
+	auth, err := NewBotAuth(testBotToken, BotAuthOptions{BotUsername: "mybot"})
+	if err != nil {
+		t.Fatalf("NewBotAuth() error = %v", err)
+	}
+
+	if _, err := auth.Poll(ctx, "unknown"); !errors.Is(err, ErrBotAuthTokenNotFound) {
+		t.Fatalf("Poll() error = %v, want %v", err, ErrBotAuthTokenNotFound)
+	}
+}
+
+func TestMemoryBotAuthStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBotAuthStore()
+
+	if err := store.Create(ctx, "expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := store.Create(ctx, "fresh", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := store.Sweep(ctx, time.Now()); err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+
+	if _, ok, _ := store.Get(ctx, "expired"); ok {
+		t.Fatal("Get() found expired token after sweep")
+	}
+
+	if _, ok, _ := store.Get(ctx, "fresh"); !ok {
+		t.Fatal("Get() did not find fresh token after sweep")
+	}
+}
+
+func TestMemoryBotAuthStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBotAuthStore()
+
+	if err := store.Create(ctx, "token", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := store.Delete(ctx, "token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, ok, _ := store.Get(ctx, "token"); ok {
+		t.Fatal("Get() found token after Delete")
+	}
+}
+
+func TestBotAuthRunReturnsNilOnContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"result":[]}`)
+	}))
+	defer server.Close()
+
+	auth, err := NewBotAuth(testBotToken, BotAuthOptions{
+		BotUsername:   "mybot",
+		PollInterval:  10 * time.Millisecond,
+		SweepInterval: time.Hour,
+		HTTPClient:    newTestServerClient(t, server),
+	})
+	if err != nil {
+		t.Fatalf("NewBotAuth() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- auth.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+}
+
+func TestBotAuthRunSurfacesPersistentGetUpdatesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"bad request"}`)
+	}))
+	defer server.Close()
+
+	auth, err := NewBotAuth(testBotToken, BotAuthOptions{
+		BotUsername: "mybot",
+		// PollInterval is short and SweepInterval deliberately long: if Run
+		// did not cancel the sibling sweep loop on a persistent getUpdates
+		// error, it would block on wg.Wait() indefinitely instead of
+		// returning within this test's timeout.
+		PollInterval:  10 * time.Millisecond,
+		SweepInterval: time.Hour,
+		HTTPClient:    newTestServerClient(t, server),
+	})
+	if err != nil {
+		t.Fatalf("NewBotAuth() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- auth.Run(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Run() error = nil, want a surfaced getUpdates failure")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after a persistent getUpdates error")
+	}
+}
+
+func TestBotAuthRunBackoffOn429ThenResolves(t *testing.T) {
+	var calls int32
+	var mu sync.Mutex
+	var token string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		mu.Lock()
+		tok := token
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ok":true,"result":[{"update_id":1,"message":{"text":"/start %s","from":{"id":42,"username":"john_doe"}}}]}`, tok)
+	}))
+	defer server.Close()
+
+	auth, err := NewBotAuth(testBotToken, BotAuthOptions{
+		BotUsername:   "mybot",
+		PollInterval:  10 * time.Millisecond,
+		SweepInterval: time.Hour,
+		HTTPClient:    newTestServerClient(t, server),
+	})
+	if err != nil {
+		t.Fatalf("NewBotAuth() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tok, _, err := auth.Request(ctx)
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+
+	mu.Lock()
+	token = tok
+	mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- auth.Run(ctx)
+	}()
+
+	var data AuthData
+	confirmed := false
+	for i := 0; i < 50; i++ {
+		data, err = auth.Poll(ctx, tok)
+		if err == nil {
+			confirmed = true
+			break
+		}
+		if !errors.Is(err, ErrBotAuthPending) {
+			t.Fatalf("Poll() error = %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !confirmed {
+		t.Fatal("token was not confirmed before deadline")
+	}
+
+	if data.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", data.UserID, 42)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+
+	if got := atomic.LoadInt32(&calls); got < 2 {
+		t.Fatalf("getUpdates calls = %d, want at least 2 (a 429 followed by a successful retry)", got)
+	}
+}
+
+func TestStartCommandToken(t *testing.T) {
+	token, ok := startCommandToken("/start abc123")
+	if !ok || token != "abc123" {
+		t.Fatalf("startCommandToken() = (%q, %v), want (%q, true)", token, ok, "abc123")
+	}
+
+	if _, ok := startCommandToken("hello"); ok {
+		t.Fatal("startCommandToken() matched unexpected text")
+	}
+}
@@ -0,0 +1,114 @@
+package telegramauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore records hashes that have already been accepted by
+// VerifyWithConfig so a captured callback URL can't be replayed within its
+// auth_date window. Implementations must be safe for concurrent use.
+//
+// A Redis-backed implementation can use SET hash "" EX ttl NX and report
+// Seen as the inverse of whether the SET succeeded:
+//
+//	func (s *redisNonceStore) Seen(ctx context.Context, hash string, ttl time.Duration) (bool, error) {
+//		ok, err := s.client.SetNX(ctx, hash, "", ttl).Result()
+//		if err != nil {
+//			return false, err
+//		}
+//		return !ok, nil
+//	}
+type NonceStore interface {
+	// Seen records hash as used for ttl and reports whether it had already
+	// been recorded before this call.
+	Seen(ctx context.Context, hash string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore is the default in-memory NonceStore, backed by a map of
+// hash to expiry time with a lazily-started janitor goroutine that evicts
+// expired entries.
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	closeCh chan struct{}
+	started bool
+}
+
+// NewMemoryNonceStore returns a NonceStore backed by an in-process map. It
+// is suitable for single-instance deployments; multi-instance deployments
+// should implement NonceStore against a shared store such as Redis so that
+// replay protection holds across instances.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{seen: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Seen(_ context.Context, hash string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.startJanitorLocked()
+
+	now := time.Now()
+	if expiresAt, ok := s.seen[hash]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+
+	s.seen[hash] = now.Add(ttl)
+
+	return false, nil
+}
+
+// startJanitorLocked starts the background eviction goroutine on first use.
+// Callers must hold s.mu.
+func (s *MemoryNonceStore) startJanitorLocked() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	s.closeCh = make(chan struct{})
+
+	go s.runJanitor(s.closeCh)
+}
+
+func (s *MemoryNonceStore) runJanitor(closeCh chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+			s.evictExpired()
+		}
+	}
+}
+
+func (s *MemoryNonceStore) evictExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, expiresAt := range s.seen {
+		if now.After(expiresAt) {
+			delete(s.seen, hash)
+		}
+	}
+}
+
+// Close stops the background janitor goroutine. It is safe to call Close
+// more than once.
+func (s *MemoryNonceStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		close(s.closeCh)
+		s.started = false
+	}
+
+	return nil
+}
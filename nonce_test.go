@@ -0,0 +1,77 @@
+package telegramauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStoreSeen(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore()
+	defer store.Close()
+
+	seen, err := store.Seen(ctx, "abc", time.Minute)
+	if err != nil {
+		t.Fatalf("Seen() error = %v", err)
+	}
+
+	if seen {
+		t.Fatal("Seen() = true on first call, want false")
+	}
+
+	seen, err = store.Seen(ctx, "abc", time.Minute)
+	if err != nil {
+		t.Fatalf("Seen() error = %v", err)
+	}
+
+	if !seen {
+		t.Fatal("Seen() = false on second call, want true")
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore()
+	defer store.Close()
+
+	if _, err := store.Seen(ctx, "abc", -time.Second); err != nil {
+		t.Fatalf("Seen() error = %v", err)
+	}
+
+	seen, err := store.Seen(ctx, "abc", time.Minute)
+	if err != nil {
+		t.Fatalf("Seen() error = %v", err)
+	}
+
+	if seen {
+		t.Fatal("Seen() = true for already-expired entry, want false")
+	}
+}
+
+func TestVerifyWithConfigReplayProtection(t *testing.T) {
+	now := time.Unix(1800000000, 0)
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": "1800000000",
+	}
+	query["hash"] = signQuery(query)
+
+	store := NewMemoryNonceStore()
+	defer store.Close()
+
+	config := VerifyConfig{
+		Now:         func() time.Time { return now },
+		ReplayStore: store,
+	}
+
+	if _, err := VerifyWithConfig(query, testBotToken, config); err != nil {
+		t.Fatalf("VerifyWithConfig() first call error = %v", err)
+	}
+
+	_, err := VerifyWithConfig(query, testBotToken, config)
+	if !errors.Is(err, ErrTelegramHashReplayed) {
+		t.Fatalf("VerifyWithConfig() second call error = %v, want %v", err, ErrTelegramHashReplayed)
+	}
+}
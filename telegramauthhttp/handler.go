@@ -0,0 +1,147 @@
+// Package telegramauthhttp wires telegramauth verification into net/http,
+// so callers don't have to hand-parse query strings, JSON bodies, or form
+// data before calling into the parent package themselves.
+package telegramauthhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ffanatik/telegram-auth"
+)
+
+// ErrMethodNotAllowed indicates that Handler received a method other than
+// GET or POST.
+var ErrMethodNotAllowed = errors.New("telegramauthhttp: method not allowed")
+
+// ErrInitDataRequired indicates that a POST request carried neither a
+// Login Widget payload nor a Mini App initData value.
+var ErrInitDataRequired = errors.New("telegramauthhttp: initData or login widget fields are required")
+
+// ctxKeyAuthData is the unexported type behind AuthDataContextKey, to keep
+// callers from colliding with it.
+type ctxKeyAuthData struct{}
+
+// AuthDataContextKey is the context.Context key Middleware stores the
+// verified telegramauth.AuthData under.
+var AuthDataContextKey = ctxKeyAuthData{}
+
+// SuccessFunc handles a successfully verified request.
+type SuccessFunc func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData)
+
+// ErrorFunc handles a request that failed verification.
+type ErrorFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// Handler returns an http.Handler that verifies incoming Telegram auth
+// requests and dispatches to onSuccess or onError.
+//
+// GET requests are treated as a Login Widget redirect and verified from the
+// query string. POST requests are read as either a JSON body or form data;
+// if an "initData" field is present it is verified as Mini App initData,
+// otherwise the fields are verified as a Login Widget payload.
+func Handler(botToken string, cfg telegramauth.VerifyConfig, onSuccess SuccessFunc, onError ErrorFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := verifyRequest(r, botToken, cfg)
+		if err != nil {
+			onError(w, r, err)
+			return
+		}
+
+		onSuccess(w, r, data)
+	})
+}
+
+// Middleware returns middleware that verifies incoming Telegram auth
+// requests the same way Handler does, stores the result in the request
+// context under AuthDataContextKey, and calls next. On failure it invokes
+// onError instead of calling next.
+func Middleware(botToken string, cfg telegramauth.VerifyConfig, onError ErrorFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, err := verifyRequest(r, botToken, cfg)
+			if err != nil {
+				onError(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), AuthDataContextKey, data)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromContext returns the telegramauth.AuthData stored by Middleware, if any.
+func FromContext(ctx context.Context) (telegramauth.AuthData, bool) {
+	data, ok := ctx.Value(AuthDataContextKey).(telegramauth.AuthData)
+
+	return data, ok
+}
+
+func verifyRequest(r *http.Request, botToken string, cfg telegramauth.VerifyConfig) (telegramauth.AuthData, error) {
+	switch r.Method {
+	case http.MethodGet:
+		return telegramauth.VerifyWithConfig(queryToMap(r.URL.Query()), botToken, cfg)
+	case http.MethodPost:
+		return verifyPost(r, botToken, cfg)
+	default:
+		return telegramauth.AuthData{}, ErrMethodNotAllowed
+	}
+}
+
+func verifyPost(r *http.Request, botToken string, cfg telegramauth.VerifyConfig) (telegramauth.AuthData, error) {
+	if isJSONContentType(r.Header.Get("Content-Type")) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return telegramauth.AuthData{}, err
+		}
+
+		var fields map[string]string
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return telegramauth.AuthData{}, err
+		}
+
+		if initData, ok := fields["initData"]; ok && initData != "" {
+			return telegramauth.VerifyWebAppInitData(initData, botToken, cfg)
+		}
+
+		if len(fields) == 0 {
+			return telegramauth.AuthData{}, ErrInitDataRequired
+		}
+
+		return telegramauth.VerifyWithConfig(fields, botToken, cfg)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return telegramauth.AuthData{}, err
+	}
+
+	if initData := r.PostForm.Get("initData"); initData != "" {
+		return telegramauth.VerifyWebAppInitData(initData, botToken, cfg)
+	}
+
+	if len(r.PostForm) == 0 {
+		return telegramauth.AuthData{}, ErrInitDataRequired
+	}
+
+	return telegramauth.VerifyWithConfig(queryToMap(r.PostForm), botToken, cfg)
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+
+	return strings.EqualFold(mediaType, "application/json")
+}
+
+func queryToMap(values url.Values) map[string]string {
+	query := make(map[string]string, len(values))
+	for key := range values {
+		query[key] = values.Get(key)
+	}
+
+	return query
+}
@@ -0,0 +1,372 @@
+package telegramauthhttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ffanatik/telegram-auth"
+)
+
+const testBotToken = "test-token"
+
+func TestHandlerGETLoginWidget(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+		"username":  "john_doe",
+	}
+	query["hash"] = signQuery(query)
+
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth?"+values.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+	var gotErr error
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		gotData = data
+		w.WriteHeader(http.StatusOK)
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotErr != nil {
+		t.Fatalf("onError called with %v", gotErr)
+	}
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+}
+
+func TestHandlerPOSTFormLoginWidget(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+	}
+	query["hash"] = signQuery(query)
+
+	form := url.Values{}
+	for key, value := range query {
+		form.Set(key, value)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		gotData = data
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("onError called with %v", err)
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+}
+
+func TestHandlerPOSTJSONLoginWidget(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+	}
+	query["hash"] = signQuery(query)
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		gotData = data
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("onError called with %v", err)
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+}
+
+func TestHandlerPOSTInitDataDispatchesToWebAppVerifier(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	initDataFields := map[string]string{
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+		"user":      `{"id":42,"username":"john_doe"}`,
+	}
+	initDataFields["hash"] = signWebAppQuery(initDataFields)
+
+	initData := url.Values{}
+	for key, value := range initDataFields {
+		initData.Set(key, value)
+	}
+
+	form := url.Values{}
+	form.Set("initData", initData.Encode())
+
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		gotData = data
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("onError called with %v", err)
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+
+	if gotData.Username != "john_doe" {
+		t.Fatalf("Username = %q, want %q", gotData.Username, "john_doe")
+	}
+}
+
+func TestHandlerPOSTJSONInitDataDispatchesToWebAppVerifier(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	initDataFields := map[string]string{
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+		"user":      `{"id":42,"username":"john_doe"}`,
+	}
+	initDataFields["hash"] = signWebAppQuery(initDataFields)
+
+	initData := url.Values{}
+	for key, value := range initDataFields {
+		initData.Set(key, value)
+	}
+
+	body, err := json.Marshal(map[string]string{"initData": initData.Encode()})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		gotData = data
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("onError called with %v", err)
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+
+	if gotData.Username != "john_doe" {
+		t.Fatalf("Username = %q, want %q", gotData.Username, "john_doe")
+	}
+}
+
+func TestHandlerPOSTJSONEmptyBodyRequiresInitData(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	var gotErr error
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		t.Fatal("onSuccess called")
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if !errors.Is(gotErr, ErrInitDataRequired) {
+		t.Fatalf("onError err = %v, want %v", gotErr, ErrInitDataRequired)
+	}
+}
+
+func TestHandlerPOSTFormEmptyBodyRequiresInitData(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	var gotErr error
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		t.Fatal("onSuccess called")
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if !errors.Is(gotErr, ErrInitDataRequired) {
+		t.Fatalf("onError err = %v, want %v", gotErr, ErrInitDataRequired)
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/auth", nil)
+	rec := httptest.NewRecorder()
+
+	var gotErr error
+
+	handler := Handler(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, data telegramauth.AuthData) {
+		t.Fatal("onSuccess called")
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+	})
+
+	handler.ServeHTTP(rec, req)
+
+	if gotErr != ErrMethodNotAllowed {
+		t.Fatalf("onError err = %v, want %v", gotErr, ErrMethodNotAllowed)
+	}
+}
+
+func TestMiddlewareStoresAuthDataInContext(t *testing.T) {
+	nowUnix := time.Now().Unix()
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(nowUnix, 10),
+	}
+	query["hash"] = signQuery(query)
+
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth?"+values.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	var gotData telegramauth.AuthData
+	var gotOK bool
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotData, gotOK = FromContext(r.Context())
+	})
+
+	middleware := Middleware(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Fatalf("onError called with %v", err)
+	})
+
+	middleware(next).ServeHTTP(rec, req)
+
+	if !gotOK {
+		t.Fatal("FromContext() ok = false, want true")
+	}
+
+	if gotData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", gotData.UserID, 42)
+	}
+}
+
+func TestMiddlewareCallsOnErrorAndSkipsNext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/auth", nil)
+	rec := httptest.NewRecorder()
+
+	var gotErr error
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next called")
+	})
+
+	middleware := Middleware(testBotToken, telegramauth.VerifyConfig{}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	middleware(next).ServeHTTP(rec, req)
+
+	if gotErr != ErrMethodNotAllowed {
+		t.Fatalf("onError err = %v, want %v", gotErr, ErrMethodNotAllowed)
+	}
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func signQuery(query map[string]string) string {
+	dataCheckPairs := make([]string, 0, len(query))
+	for key, value := range query {
+		if key == "hash" {
+			continue
+		}
+
+		dataCheckPairs = append(dataCheckPairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(dataCheckPairs)
+	dataCheckString := strings.Join(dataCheckPairs, "\n")
+
+	secret := sha256.Sum256([]byte(testBotToken))
+	hasher := hmac.New(sha256.New, secret[:])
+	_, _ = hasher.Write([]byte(dataCheckString))
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func signWebAppQuery(query map[string]string) string {
+	dataCheckPairs := make([]string, 0, len(query))
+	for key, value := range query {
+		if key == "hash" {
+			continue
+		}
+
+		dataCheckPairs = append(dataCheckPairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(dataCheckPairs)
+	dataCheckString := strings.Join(dataCheckPairs, "\n")
+
+	secretHasher := hmac.New(sha256.New, []byte("WebAppData"))
+	secretHasher.Write([]byte(testBotToken))
+	secret := secretHasher.Sum(nil)
+
+	hasher := hmac.New(sha256.New, secret)
+	_, _ = hasher.Write([]byte(dataCheckString))
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
@@ -1,9 +1,11 @@
 package telegramauth
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -39,8 +41,18 @@ var (
 	ErrTelegramAuthDateExpired = errors.New("telegram auth_date is expired")
 	// ErrTelegramAuthDateFuture indicates that auth_date is too far in the future.
 	ErrTelegramAuthDateFuture = errors.New("telegram auth_date is from future")
+	// ErrWebAppUserRequired indicates that the initData user field is missing.
+	ErrWebAppUserRequired = errors.New("webapp user is required")
+	// ErrWebAppUserInvalid indicates that the initData user field is not valid JSON.
+	ErrWebAppUserInvalid = errors.New("webapp user is invalid")
+	// ErrTelegramHashReplayed indicates that a ReplayStore has already seen this hash.
+	ErrTelegramHashReplayed = errors.New("telegram hash was already used")
 )
 
+// webAppDataSecretKey is the fixed key Telegram uses to derive the
+// Mini App secret via HMAC-SHA256(key="WebAppData", data=botToken).
+const webAppDataSecretKey = "WebAppData"
+
 // VerifyConfig configures VerifyWithConfig behavior.
 type VerifyConfig struct {
 	// AuthTTL sets maximum allowed age for auth_date.
@@ -52,6 +64,22 @@ type VerifyConfig struct {
 	// Now overrides current time source.
 	// Nil uses time.Now.
 	Now func() time.Time
+	// ReplayStore, when set, rejects callback data whose hash has already
+	// been seen within AuthTTL+ClockSkew of a prior accepted call.
+	// Nil disables replay protection.
+	ReplayStore NonceStore
+	// BotTokens lists additional bot tokens to try after the primary
+	// botToken argument, for teams rotating tokens or proxying several
+	// bots through one backend.
+	BotTokens []string
+	// BotTokenSource, when set, is called to fetch additional bot tokens
+	// to try, appended after BotTokens. Useful when tokens are rotated at
+	// runtime and shouldn't be baked into VerifyConfig ahead of time.
+	BotTokenSource func(ctx context.Context) ([]string, error)
+	// OnTokenMatched, when set, is called with the index into the
+	// effective token list (0 = the primary botToken argument) of the
+	// token that verified the hash.
+	OnTokenMatched func(index int)
 }
 
 // AuthData contains validated Telegram user fields from callback data.
@@ -112,10 +140,26 @@ func VerifyWithConfig(query map[string]string, botToken string, config VerifyCon
 		return AuthData{}, ErrTelegramHashRequired
 	}
 
-	if err := verifyHash(query, botToken, hash); err != nil {
+	botTokens, err := effectiveBotTokens(context.Background(), botToken, config)
+	if err != nil {
 		return AuthData{}, err
 	}
 
+	if err := verifyHashAnyToken(query, botTokens, hash, config.OnTokenMatched, botTokenSecret); err != nil {
+		return AuthData{}, err
+	}
+
+	if config.ReplayStore != nil {
+		seen, err := config.ReplayStore.Seen(context.Background(), hash, authTTL+clockSkew)
+		if err != nil {
+			return AuthData{}, fmt.Errorf("check replay store: %w", err)
+		}
+
+		if seen {
+			return AuthData{}, ErrTelegramHashReplayed
+		}
+	}
+
 	idValue := strings.TrimSpace(query["id"])
 	if idValue == "" {
 		return AuthData{}, ErrTelegramIDRequired
@@ -160,7 +204,174 @@ func VerifyWithConfig(query map[string]string, botToken string, config VerifyCon
 	}, nil
 }
 
-func verifyHash(query map[string]string, botToken, expectedHash string) error {
+// webAppUser mirrors the subset of Telegram's WebAppUser object that
+// populates AuthData from a Mini App initData "user" field.
+type webAppUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	PhotoURL  string `json:"photo_url"`
+}
+
+// VerifyWebAppInitData validates a Telegram Mini App Telegram.WebApp.initData
+// string and returns the authenticated user data it carries.
+func VerifyWebAppInitData(initData string, botToken string, config VerifyConfig) (AuthData, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return AuthData{}, fmt.Errorf("%w: %v", ErrTelegramHashInvalid, err)
+	}
+
+	return VerifyWebAppURLValues(values, botToken, config)
+}
+
+// VerifyWebAppURLValues validates Telegram Mini App initData already parsed
+// into url.Values.
+func VerifyWebAppURLValues(values url.Values, botToken string, config VerifyConfig) (AuthData, error) {
+	botToken = strings.TrimSpace(botToken)
+	if botToken == "" {
+		return AuthData{}, ErrBotTokenRequired
+	}
+
+	authTTL := config.AuthTTL
+	if authTTL <= 0 {
+		authTTL = DefaultAuthTTL
+	}
+
+	clockSkew := config.ClockSkew
+	if clockSkew <= 0 {
+		clockSkew = DefaultClockSkew
+	}
+
+	nowFn := config.Now
+	if nowFn == nil {
+		nowFn = time.Now
+	}
+
+	query := make(map[string]string, len(values))
+	for key := range values {
+		query[key] = values.Get(key)
+	}
+
+	hash := strings.TrimSpace(query["hash"])
+	if hash == "" {
+		return AuthData{}, ErrTelegramHashRequired
+	}
+
+	botTokens, err := effectiveBotTokens(context.Background(), botToken, config)
+	if err != nil {
+		return AuthData{}, err
+	}
+
+	if err := verifyHashAnyToken(query, botTokens, hash, config.OnTokenMatched, webAppTokenSecret); err != nil {
+		return AuthData{}, err
+	}
+
+	userValue := strings.TrimSpace(query["user"])
+	if userValue == "" {
+		return AuthData{}, ErrWebAppUserRequired
+	}
+
+	var user webAppUser
+	if err := json.Unmarshal([]byte(userValue), &user); err != nil {
+		return AuthData{}, fmt.Errorf("%w: %v", ErrWebAppUserInvalid, err)
+	}
+
+	if user.ID <= 0 {
+		return AuthData{}, fmt.Errorf("%w: %q", ErrTelegramIDInvalid, userValue)
+	}
+
+	authDateValue := strings.TrimSpace(query["auth_date"])
+	if authDateValue == "" {
+		return AuthData{}, ErrTelegramAuthDateRequired
+	}
+
+	authDateUnix, err := strconv.ParseInt(authDateValue, 10, 64)
+	if err != nil {
+		return AuthData{}, fmt.Errorf("%w: %q", ErrTelegramAuthDateInvalid, authDateValue)
+	}
+
+	authDate := time.Unix(authDateUnix, 0)
+	now := nowFn()
+	if authDate.After(now.Add(clockSkew)) {
+		return AuthData{}, ErrTelegramAuthDateFuture
+	}
+
+	if now.Sub(authDate) > authTTL {
+		return AuthData{}, ErrTelegramAuthDateExpired
+	}
+
+	return AuthData{
+		UserID:       user.ID,
+		Username:     user.Username,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		PhotoURL:     user.PhotoURL,
+		AuthDateUnix: authDateUnix,
+	}, nil
+}
+
+// effectiveBotTokens builds the ordered list of bot tokens a verifier should
+// try: the primary botToken argument first, then config.BotTokens, then any
+// tokens config.BotTokenSource sources dynamically. Both the Login Widget
+// and Mini App verifiers share this so rotation configured once on
+// VerifyConfig applies to both code paths.
+func effectiveBotTokens(ctx context.Context, botToken string, config VerifyConfig) ([]string, error) {
+	botTokens := make([]string, 0, len(config.BotTokens)+1)
+	botTokens = append(botTokens, botToken)
+	botTokens = append(botTokens, config.BotTokens...)
+
+	if config.BotTokenSource != nil {
+		sourced, err := config.BotTokenSource(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch bot tokens: %w", err)
+		}
+
+		botTokens = append(botTokens, sourced...)
+	}
+
+	return botTokens, nil
+}
+
+// botTokenSecret derives the Login Widget HMAC secret key for botToken.
+func botTokenSecret(botToken string) []byte {
+	secret := sha256.Sum256([]byte(botToken))
+
+	return secret[:]
+}
+
+// webAppTokenSecret derives the Mini App HMAC secret key for botToken:
+// HMAC_SHA256(key="WebAppData", data=botToken).
+func webAppTokenSecret(botToken string) []byte {
+	hasher := hmac.New(sha256.New, []byte(webAppDataSecretKey))
+	_, _ = hasher.Write([]byte(botToken))
+
+	return hasher.Sum(nil)
+}
+
+// verifyHashAnyToken tries each bot token in order, deriving its secret via
+// secretFor, and returns success on the first one whose secret's HMAC
+// matches expectedHash. It reports ErrTelegramHashInvalid only once every
+// token has been tried and failed.
+func verifyHashAnyToken(query map[string]string, botTokens []string, expectedHash string, onMatched func(index int), secretFor func(botToken string) []byte) error {
+	for index, botToken := range botTokens {
+		if verifyHashWithSecret(query, secretFor(botToken), expectedHash) == nil {
+			if onMatched != nil {
+				onMatched(index)
+			}
+
+			return nil
+		}
+	}
+
+	return ErrTelegramHashInvalid
+}
+
+// verifyHashWithSecret checks expectedHash against the HMAC-SHA256 of the
+// data-check-string derived from query, using a precomputed secret key.
+// Both the Login Widget and Mini App verifiers share this logic; only the
+// secret key derivation differs between them.
+func verifyHashWithSecret(query map[string]string, secret []byte, expectedHash string) error {
 	expectedHashBytes, err := hex.DecodeString(strings.TrimSpace(expectedHash))
 	if err != nil {
 		return ErrTelegramHashInvalid
@@ -178,8 +389,7 @@ func verifyHash(query map[string]string, botToken, expectedHash string) error {
 	sort.Strings(dataCheckPairs)
 	dataCheckString := strings.Join(dataCheckPairs, "\n")
 
-	secret := sha256.Sum256([]byte(botToken))
-	hasher := hmac.New(sha256.New, secret[:])
+	hasher := hmac.New(sha256.New, secret)
 	_, _ = hasher.Write([]byte(dataCheckString))
 	calculatedHashBytes := hasher.Sum(nil)
 
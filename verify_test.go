@@ -189,6 +189,203 @@ func TestVerifyBotTokenRequired(t *testing.T) {
 	}
 }
 
+func TestVerifyWebAppInitDataValid(t *testing.T) {
+	now := time.Unix(1800000000, 0)
+	query := map[string]string{
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+		"query_id":  "AAH123",
+		"user":      `{"id":42,"username":"john_doe","first_name":"John","last_name":"Doe"}`,
+	}
+	query["hash"] = signWebAppQuery(query)
+
+	initData := url.Values{}
+	for key, value := range query {
+		initData.Set(key, value)
+	}
+
+	authData, err := VerifyWebAppInitData(initData.Encode(), testBotToken, VerifyConfig{Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("VerifyWebAppInitData() error = %v", err)
+	}
+
+	if authData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", authData.UserID, 42)
+	}
+
+	if authData.Username != "john_doe" {
+		t.Fatalf("Username = %q, want %q", authData.Username, "john_doe")
+	}
+}
+
+func TestVerifyWebAppInitDataInvalidHash(t *testing.T) {
+	query := map[string]string{
+		"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+		"user":      `{"id":42}`,
+		"hash":      "deadbeef",
+	}
+
+	initData := url.Values{}
+	for key, value := range query {
+		initData.Set(key, value)
+	}
+
+	_, err := VerifyWebAppInitData(initData.Encode(), testBotToken, VerifyConfig{})
+	if !errors.Is(err, ErrTelegramHashInvalid) {
+		t.Fatalf("VerifyWebAppInitData() error = %v, want %v", err, ErrTelegramHashInvalid)
+	}
+}
+
+func TestVerifyWebAppInitDataInvalidUser(t *testing.T) {
+	query := map[string]string{
+		"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+		"user":      `not-json`,
+	}
+	query["hash"] = signWebAppQuery(query)
+
+	initData := url.Values{}
+	for key, value := range query {
+		initData.Set(key, value)
+	}
+
+	_, err := VerifyWebAppInitData(initData.Encode(), testBotToken, VerifyConfig{})
+	if !errors.Is(err, ErrWebAppUserInvalid) {
+		t.Fatalf("VerifyWebAppInitData() error = %v, want %v", err, ErrWebAppUserInvalid)
+	}
+}
+
+func TestVerifyWebAppURLValuesBotTokensFallback(t *testing.T) {
+	const oldToken = "old-token"
+
+	now := time.Unix(1800000000, 0)
+	query := map[string]string{
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+		"user":      `{"id":42,"username":"john_doe"}`,
+	}
+	query["hash"] = signWebAppQueryWithToken(query, oldToken)
+
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	var matchedIndex = -1
+	config := VerifyConfig{
+		Now:            func() time.Time { return now },
+		BotTokens:      []string{oldToken},
+		OnTokenMatched: func(index int) { matchedIndex = index },
+	}
+
+	authData, err := VerifyWebAppURLValues(values, testBotToken, config)
+	if err != nil {
+		t.Fatalf("VerifyWebAppURLValues() error = %v", err)
+	}
+
+	if authData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", authData.UserID, 42)
+	}
+
+	if matchedIndex != 1 {
+		t.Fatalf("matchedIndex = %d, want %d", matchedIndex, 1)
+	}
+}
+
+func signWebAppQueryWithToken(query map[string]string, botToken string) string {
+	dataCheckPairs := make([]string, 0, len(query))
+	for key, value := range query {
+		if key == "hash" {
+			continue
+		}
+
+		dataCheckPairs = append(dataCheckPairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(dataCheckPairs)
+	dataCheckString := strings.Join(dataCheckPairs, "\n")
+
+	secretHasher := hmac.New(sha256.New, []byte("WebAppData"))
+	secretHasher.Write([]byte(botToken))
+	secret := secretHasher.Sum(nil)
+
+	hasher := hmac.New(sha256.New, secret)
+	_, _ = hasher.Write([]byte(dataCheckString))
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func signWebAppQuery(query map[string]string) string {
+	return signWebAppQueryWithToken(query, testBotToken)
+}
+
+func TestVerifyWithConfigBotTokensFallback(t *testing.T) {
+	const oldToken = "old-token"
+
+	now := time.Unix(1800000000, 0)
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+	}
+	query["hash"] = signQueryWithToken(query, oldToken)
+
+	var matchedIndex = -1
+	config := VerifyConfig{
+		Now:            func() time.Time { return now },
+		BotTokens:      []string{oldToken},
+		OnTokenMatched: func(index int) { matchedIndex = index },
+	}
+
+	authData, err := VerifyWithConfig(query, testBotToken, config)
+	if err != nil {
+		t.Fatalf("VerifyWithConfig() error = %v", err)
+	}
+
+	if authData.UserID != 42 {
+		t.Fatalf("UserID = %d, want %d", authData.UserID, 42)
+	}
+
+	if matchedIndex != 1 {
+		t.Fatalf("matchedIndex = %d, want %d", matchedIndex, 1)
+	}
+}
+
+func TestVerifyWithConfigBotTokensAllInvalid(t *testing.T) {
+	now := time.Unix(1800000000, 0)
+	query := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+	}
+	query["hash"] = signQueryWithToken(query, "unrelated-token")
+
+	config := VerifyConfig{
+		Now:       func() time.Time { return now },
+		BotTokens: []string{"another-old-token"},
+	}
+
+	_, err := VerifyWithConfig(query, testBotToken, config)
+	if !errors.Is(err, ErrTelegramHashInvalid) {
+		t.Fatalf("VerifyWithConfig() error = %v, want %v", err, ErrTelegramHashInvalid)
+	}
+}
+
+func signQueryWithToken(query map[string]string, botToken string) string {
+	dataCheckPairs := make([]string, 0, len(query))
+	for key, value := range query {
+		if key == "hash" {
+			continue
+		}
+
+		dataCheckPairs = append(dataCheckPairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(dataCheckPairs)
+	dataCheckString := strings.Join(dataCheckPairs, "\n")
+
+	secret := sha256.Sum256([]byte(botToken))
+	hasher := hmac.New(sha256.New, secret[:])
+	_, _ = hasher.Write([]byte(dataCheckString))
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
 func signQuery(query map[string]string) string {
 	dataCheckPairs := make([]string, 0, len(query))
 	for key, value := range query {